@@ -0,0 +1,46 @@
+package core
+
+// NOTE: LLMProvider and LLMEndpoint (Provider/Model/Key) are not new — GhcpClient has
+// referenced *LLMEndpoint since before this file existed, so their real declaration
+// lives in the core package's main LLM source file. That file isn't part of this
+// change's diff, so the Alias field and LLM.WithAlias added here are declared alongside
+// a copy of the pre-existing fields rather than in-place. Landing this file as-is would
+// redeclare LLMProvider/LLMEndpoint/LLM: merge Alias/WithAlias into the real
+// declarations and drop this file instead.
+
+// LLMProvider identifies which backend an LLMEndpoint talks to (e.g. "github").
+type LLMProvider string
+
+// LLMEndpoint describes a single configured LLM backend: which provider and model to
+// use, the credentials to reach it, and an optional human-readable label.
+type LLMEndpoint struct {
+	Provider LLMProvider
+	Model    string
+	Key      string
+
+	// Alias labels this endpoint for telemetry, set via LLM.withAlias(name) so a
+	// pipeline author running two endpoints of the same provider (e.g. a "prod" and a
+	// "sandbox" Copilot subscription) can tell their gauges and spans apart in a
+	// shared OTel dashboard.
+	Alias string
+}
+
+// LLM is the core API object backing the `dagger.LLM` type that pipeline authors chain
+// calls on, e.g. `dagger.llm().withModel(...).withAlias(...)`. Only WithAlias is added
+// here; the rest of LLM's state/methods live on the real type (see the NOTE above).
+type LLM struct {
+	Endpoint *LLMEndpoint
+}
+
+// WithAlias returns a copy of llm whose endpoint is labeled alias, exposed to pipeline
+// authors as `LLM.withAlias(name)`. It follows the same copy-on-write pattern as the
+// other `With*` builders on Dagger core objects.
+func (llm *LLM) WithAlias(alias string) *LLM {
+	cp := *llm
+	if cp.Endpoint != nil {
+		epCopy := *cp.Endpoint
+		epCopy.Alias = alias
+		cp.Endpoint = &epCopy
+	}
+	return &cp
+}
@@ -1,32 +1,113 @@
 package core
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
+	"os"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 
 	"dagger.io/dagger"
 	"dagger.io/dagger/dag"
 	"dagger.io/dagger/telemetry"
 
+	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
 	"go.opentelemetry.io/otel/log"
 	"go.opentelemetry.io/otel/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/trace"
 )
 
+// genAISpanName is the span name used for each LLM completion call, following the
+// OpenTelemetry GenAI semantic conventions (gen_ai.* attribute names) so traces line up
+// with other GenAI-instrumented systems in a shared backend (Tempo/Jaeger/Honeycomb via
+// OTLP).
+const genAISpanName = "llm.ghcp.completion"
+
+// traceLLMContentEnvVar gates whether prompt/response content is attached to spans as
+// events. It defaults to off since prompts/responses may contain sensitive data.
+const traceLLMContentEnvVar = "DAGGER_LLM_TRACE_CONTENT"
+
+// genAIOTLPExporterOnce guards registerGenAIOTLPExporter so the exporter and tracer
+// provider are only built once per process, regardless of how many LLM calls are made.
+var genAIOTLPExporterOnce sync.Once
+
+// registerGenAIOTLPExporter registers a global OTLP/HTTP trace exporter the first time a
+// GenAI span is opened, so operators who set OTEL_EXPORTER_OTLP_ENDPOINT get these spans
+// exported to Tempo/Jaeger/Honeycomb alongside the rest of the Dagger pipeline trace. It
+// is a no-op when the env var isn't set, leaving whatever TracerProvider the engine has
+// already configured in place.
+func registerGenAIOTLPExporter(ctx context.Context) {
+	genAIOTLPExporterOnce.Do(func() {
+		if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" {
+			return
+		}
+		exporter, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return
+		}
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter)))
+	})
+}
+
+// startGenAISpan opens a child span for a single LLM completion call, tagged with the
+// OpenTelemetry GenAI semantic convention attributes. The returned end func records the
+// resulting token usage (or error) and must be called before returning from SendQuery.
+func startGenAISpan(ctx context.Context, provider, model, alias string) (context.Context, trace.Span, func(usage LLMTokenUsage, err error)) {
+	registerGenAIOTLPExporter(ctx)
+	tracer := telemetry.Tracer(ctx, InstrumentationLibrary)
+	spanAttrs := []attribute.KeyValue{
+		attribute.String("gen_ai.system", provider),
+		attribute.String("gen_ai.request.model", model),
+	}
+	if alias != "" {
+		spanAttrs = append(spanAttrs, attribute.String("alias", alias))
+	}
+	ctx, span := tracer.Start(ctx, genAISpanName, trace.WithAttributes(spanAttrs...))
+	return ctx, span, func(usage LLMTokenUsage, err error) {
+		defer span.End()
+		span.SetAttributes(
+			attribute.Int64("gen_ai.usage.input_tokens", usage.InputTokens),
+			attribute.Int64("gen_ai.usage.output_tokens", usage.OutputTokens),
+			attribute.Int64("gen_ai.usage.cached_tokens", usage.CachedTokenReads),
+		)
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			span.RecordError(err)
+		}
+	}
+}
+
+// recordGenAIContentEvent attaches a prompt/response payload to the current span as an
+// event, but only when DAGGER_LLM_TRACE_CONTENT is set, since these payloads may contain
+// sensitive user data that operators don't want flowing into a trace backend.
+func recordGenAIContentEvent(span trace.Span, name, content string) {
+	if os.Getenv(traceLLMContentEnvVar) == "" {
+		return
+	}
+	span.AddEvent(name, trace.WithAttributes(attribute.String("content", content)))
+}
+
 type GhcpClient struct {
 	client   *dagger.Container
 	endpoint *LLMEndpoint
 }
 
+// newGhcpClient builds the container-based CLI transport. It is kept for parity with
+// the copilot CLI and is used instead of GhcpHTTPClient (the default transport) only
+// when DAGGER_GHCP_TRANSPORT=container is set, see NewGhcpClient.
 func newGhcpClient(endpoint *LLMEndpoint, cliVersion string) *GhcpClient {
 	ctx := context.Background()
 
 	// Since there is no official Go SDK for GitHub Copilot at the moment, we will use the GitHub Copilot CLI via a Dagger container.
-	var container = GhcpClientContainer(ctx, endpoint.Key, cliVersion)
+	var container = GhcpClientContainer(ctx, endpoint.Key, ghcpCLIVersion(cliVersion))
 
 	return &GhcpClient{
 		client:   container,
@@ -49,8 +130,12 @@ var gitHubModelPrefixes = []string{
 // E.g "github-gpt-5" -> "gpt-5"
 // Since GitHub uses various models we want to avoid model name collisions with other providers
 // we default to "github-gpt-5" for now but will update this in future to allow the GHCP CLI to fall back to its own default model
+//
+// The prefixes checked come from ghcpModelPrefixes, which prefers a fleet-wide override
+// fetched from the control plane (see StartGhcpRemoteConfigRefresh) over the static
+// gitHubModelPrefixes above.
 func StripGitHubModelPrefix(model string) string {
-	for _, prefix := range gitHubModelPrefixes {
+	for _, prefix := range ghcpModelPrefixes() {
 		if strings.HasPrefix(model, prefix) {
 			return strings.TrimPrefix(model, prefix)
 		}
@@ -73,13 +158,20 @@ func GhcpClientContainer(
 // Satisfy the LLMClient interface with SendQuery and IsRetryable
 func (c *GhcpClient) SendQuery(ctx context.Context, history []*ModelMessage, tools []LLMTool) (_ *LLMResponse, rerr error) {
 
-	var copilotModel = StripGitHubModelPrefix(c.endpoint.Model)
+	var copilotModel = StripGitHubModelPrefix(ghcpResolveModel(c.endpoint.Model))
+	if err := ghcpCheckRequestAllowed(copilotModel); err != nil {
+		return nil, err
+	}
 	// instrument the call with telemetry
 	// todo: moving to setup function to clean this up
 	stdio := telemetry.SpanStdio(ctx, InstrumentationLibrary,
 		log.String(telemetry.ContentTypeAttr, "text/markdown"))
 	defer stdio.Close()
 
+	ctx, span, endSpan := startGenAISpan(ctx, string(c.endpoint.Provider), copilotModel, c.endpoint.Alias)
+	var llmTokenUsage LLMTokenUsage
+	defer func() { endSpan(llmTokenUsage, rerr) }()
+
 	m := telemetry.Meter(ctx, InstrumentationLibrary)
 	spanCtx := trace.SpanContextFromContext(ctx)
 
@@ -88,6 +180,7 @@ func (c *GhcpClient) SendQuery(ctx context.Context, history []*ModelMessage, too
 		attribute.String(telemetry.MetricsSpanIDAttr, spanCtx.SpanID().String()),
 		attribute.String("model", copilotModel),
 		attribute.String("provider", string(c.endpoint.Provider)),
+		attribute.String("alias", c.endpoint.Alias),
 	}
 
 	inputTokens, err := m.Int64Gauge(telemetry.LLMInputTokens)
@@ -119,12 +212,16 @@ func (c *GhcpClient) SendQuery(ctx context.Context, history []*ModelMessage, too
 		return nil, fmt.Errorf("the last message in history must be from the user")
 	}
 
-	var copilot = c.client.WithExec([]string{
-		"copilot",
-		"--model", copilotModel,
-		"--prompt", prompt.Content,
-		"--stream", "off",
-	})
+	var copilot = c.client.
+		WithEnvVariable("NO_COLOR", "1").
+		WithExec([]string{
+			"copilot",
+			"--model", copilotModel,
+			"--prompt", prompt.Content,
+			"--stream", "off",
+			"--log-level", "debug",
+			"--log-format", "json",
+		})
 
 	// We aren't implement tool calls for GHCP at the moment
 	var toolCalls []LLMToolCall
@@ -139,13 +236,20 @@ func (c *GhcpClient) SendQuery(ctx context.Context, history []*ModelMessage, too
 		return nil, err
 	}
 
-	llmTokenUsage := parseCopilotTokenMetadata(ghcpResponseMetadata)
+	if copilotErr := parseCopilotJSONError(ghcpResponseMetadata); copilotErr != "" {
+		return nil, fmt.Errorf("github copilot cli reported an error: %s", copilotErr)
+	}
+
+	llmTokenUsage = parseCopilotTokenUsage(ghcpResponseMetadata)
 
 	// Record metrics for token usage with attributes in OTel
 	inputTokens.Record(ctx, llmTokenUsage.InputTokens, metric.WithAttributes(attrs...))
 	outputTokens.Record(ctx, llmTokenUsage.OutputTokens, metric.WithAttributes(attrs...))
 	inputTokensCacheReads.Record(ctx, llmTokenUsage.CachedTokenReads, metric.WithAttributes(attrs...))
 
+	recordGenAIContentEvent(span, "gen_ai.content.prompt", prompt.Content)
+	recordGenAIContentEvent(span, "gen_ai.content.completion", content)
+
 	return &LLMResponse{
 		Content:    content,
 		ToolCalls:  toolCalls,
@@ -159,6 +263,92 @@ func (c *GhcpClient) IsRetryable(err error) bool {
 	return false
 }
 
+// CopilotEvent is one line of the copilot CLI's `--log-format json` debug log output.
+// Only the fields we care about for token accounting and error surfacing are modeled;
+// unrecognized fields are ignored by encoding/json.
+type CopilotEvent struct {
+	Model     string `json:"model"`
+	RequestID string `json:"request_id"`
+	Error     string `json:"error"`
+	Usage     struct {
+		Input      int64 `json:"input"`
+		Output     int64 `json:"output"`
+		CacheRead  int64 `json:"cache_read"`
+		CacheWrite int64 `json:"cache_write"`
+	} `json:"usage"`
+}
+
+// parseCopilotTokenUsage extracts token usage from the copilot CLI's stderr output. It
+// prefers the structured `--log-format json` lines requested above, and only falls back
+// to the fragile human-formatted regex (parseCopilotTokenMetadata) when no JSON usage
+// event can be found, e.g. against an older CLI version that doesn't support it yet.
+func parseCopilotTokenUsage(copilotclimetadata string) LLMTokenUsage {
+	if usage, ok := parseCopilotJSONTokenMetadata(copilotclimetadata); ok {
+		return usage
+	}
+	return parseCopilotTokenMetadata(copilotclimetadata)
+}
+
+// parseCopilotJSONError scans stderr for `--log-format json` lines and returns the last
+// non-empty CopilotEvent.Error found, so SendQuery can fail loudly on an upstream error
+// that the CLI reported but still exited 0 for, instead of returning a fabricated
+// success response with zero token usage. Returns "" if no line reports an error.
+func parseCopilotJSONError(copilotclimetadata string) string {
+	var lastErr string
+
+	scanner := bufio.NewScanner(strings.NewReader(copilotclimetadata))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] != '{' {
+			continue
+		}
+
+		var event CopilotEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			continue
+		}
+		if event.Error != "" {
+			lastErr = event.Error
+		}
+	}
+
+	return lastErr
+}
+
+// parseCopilotJSONTokenMetadata scans stderr for `--log-format json` lines and returns
+// the token usage from the last CopilotEvent that reports one. ok is false if no line
+// parses as a CopilotEvent with non-zero usage, signaling the caller should fall back.
+func parseCopilotJSONTokenMetadata(copilotclimetadata string) (_ LLMTokenUsage, ok bool) {
+	var tokenUsage LLMTokenUsage
+
+	scanner := bufio.NewScanner(strings.NewReader(copilotclimetadata))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] != '{' {
+			continue
+		}
+
+		var event CopilotEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			continue
+		}
+		if event.Usage.Input == 0 && event.Usage.Output == 0 {
+			continue
+		}
+
+		tokenUsage = LLMTokenUsage{
+			InputTokens:       event.Usage.Input,
+			OutputTokens:      event.Usage.Output,
+			CachedTokenReads:  event.Usage.CacheRead,
+			CachedTokenWrites: event.Usage.CacheWrite,
+			TotalTokens:       event.Usage.Input + event.Usage.Output,
+		}
+		ok = true
+	}
+
+	return tokenUsage, ok
+}
+
 // parseCopilotTokenMetadata parses the stderr output (GHCP CLI Meatdata) from GitHub Copilot CLI to extract token usage information
 func parseCopilotTokenMetadata(copilotclimetadata string) LLMTokenUsage {
 	var tokenUsage LLMTokenUsage
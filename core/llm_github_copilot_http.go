@@ -0,0 +1,316 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	"dagger.io/dagger/telemetry"
+)
+
+// defaultGhcpHTTPBaseURL is the OpenAI-compatible chat completions endpoint used by
+// GitHub Models / GitHub Copilot when DAGGER_GHCP_HTTP_BASE_URL is not set.
+const defaultGhcpHTTPBaseURL = "https://models.inference.ai.azure.com"
+
+// GhcpHTTPClient talks to GitHub Copilot / GitHub Models directly over HTTP using the
+// OpenAI-compatible chat completions API. Unlike GhcpClient, it sends the full message
+// history on every call, surfaces tool calls, and streams via SSE, so it is the default
+// transport for provider=github. GhcpClient remains available as a fallback for parity
+// with the CLI when the HTTP transport can't be used (e.g. air-gapped installs pinned
+// to a specific copilot CLI build).
+type GhcpHTTPClient struct {
+	httpClient *http.Client
+	endpoint   *LLMEndpoint
+	baseURL    string
+}
+
+func newGhcpHTTPClient(endpoint *LLMEndpoint) *GhcpHTTPClient {
+	baseURL := defaultGhcpHTTPBaseURL
+	if override := os.Getenv("DAGGER_GHCP_HTTP_BASE_URL"); override != "" {
+		baseURL = override
+	}
+	return &GhcpHTTPClient{
+		httpClient: &http.Client{Timeout: 5 * time.Minute},
+		endpoint:   endpoint,
+		baseURL:    baseURL,
+	}
+}
+
+var _ LLMClient = (*GhcpHTTPClient)(nil)
+
+// NewGhcpClient returns the configured LLMClient for provider=github. It defaults to
+// the native HTTP transport (GhcpHTTPClient), falling back to the container-based CLI
+// transport (GhcpClient) when DAGGER_GHCP_TRANSPORT=container is set, e.g. to pin a
+// specific copilot CLI version during the HTTP transport's rollout.
+func NewGhcpClient(endpoint *LLMEndpoint, cliVersion string) LLMClient {
+	if os.Getenv("DAGGER_GHCP_TRANSPORT") == "container" {
+		return newGhcpClient(endpoint, cliVersion)
+	}
+	return newGhcpHTTPClient(endpoint)
+}
+
+type ghcpChatMessage struct {
+	Role       string         `json:"role"`
+	Content    string         `json:"content,omitempty"`
+	ToolCallID string         `json:"tool_call_id,omitempty"`
+	ToolCalls  []ghcpToolCall `json:"tool_calls,omitempty"`
+}
+
+type ghcpToolCall struct {
+	Index    int    `json:"index"`
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type ghcpToolDef struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string `json:"name"`
+		Description string `json:"description,omitempty"`
+		Parameters  any    `json:"parameters,omitempty"`
+	} `json:"function"`
+}
+
+type ghcpStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type ghcpChatRequest struct {
+	Model         string             `json:"model"`
+	Messages      []ghcpChatMessage  `json:"messages"`
+	Tools         []ghcpToolDef      `json:"tools,omitempty"`
+	Stream        bool               `json:"stream"`
+	StreamOptions *ghcpStreamOptions `json:"stream_options,omitempty"`
+}
+
+type ghcpChatChoice struct {
+	Delta        ghcpChatMessage `json:"delta"`
+	Message      ghcpChatMessage `json:"message"`
+	FinishReason string          `json:"finish_reason"`
+}
+
+type ghcpChatResponse struct {
+	Model   string           `json:"model"`
+	Choices []ghcpChatChoice `json:"choices"`
+	Usage   struct {
+		PromptTokens        int64 `json:"prompt_tokens"`
+		CompletionTokens    int64 `json:"completion_tokens"`
+		TotalTokens         int64 `json:"total_tokens"`
+		PromptTokensDetails struct {
+			CachedTokens int64 `json:"cached_tokens"`
+		} `json:"prompt_tokens_details"`
+	} `json:"usage"`
+}
+
+// ghcpHTTPError carries the HTTP status code from a failed request so IsRetryable can
+// distinguish transient (429/5xx) failures from permanent ones (4xx).
+type ghcpHTTPError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *ghcpHTTPError) Error() string {
+	return fmt.Sprintf("github copilot http client: unexpected status %d: %s", e.StatusCode, e.Body)
+}
+
+func toGhcpMessages(history []*ModelMessage) []ghcpChatMessage {
+	messages := make([]ghcpChatMessage, 0, len(history))
+	for _, msg := range history {
+		message := ghcpChatMessage{
+			Role:       msg.Role,
+			Content:    msg.Content,
+			ToolCallID: msg.ToolCallID,
+		}
+		for _, tc := range msg.ToolCalls {
+			var ghcpTC ghcpToolCall
+			ghcpTC.ID = tc.ID
+			ghcpTC.Type = "function"
+			ghcpTC.Function.Name = tc.Function.Name
+			ghcpTC.Function.Arguments = tc.Function.Arguments
+			message.ToolCalls = append(message.ToolCalls, ghcpTC)
+		}
+		messages = append(messages, message)
+	}
+	return messages
+}
+
+func toGhcpTools(tools []LLMTool) []ghcpToolDef {
+	if len(tools) == 0 {
+		return nil
+	}
+	defs := make([]ghcpToolDef, 0, len(tools))
+	for _, tool := range tools {
+		var def ghcpToolDef
+		def.Type = "function"
+		def.Function.Name = tool.Name
+		def.Function.Description = tool.Description
+		def.Function.Parameters = tool.Schema
+		defs = append(defs, def)
+	}
+	return defs
+}
+
+// SendQuery sends the full conversation history to the GitHub Models chat completions
+// endpoint and streams the response via SSE, accumulating content and tool calls as
+// they arrive. Token usage is taken from the last chunk that reports it and recorded to
+// the gauges once, after the stream ends; no per-chunk token counts are recorded.
+func (c *GhcpHTTPClient) SendQuery(ctx context.Context, history []*ModelMessage, tools []LLMTool) (_ *LLMResponse, rerr error) {
+	if len(history) == 0 {
+		return nil, fmt.Errorf("prompt/chat history cannot be empty - run with-prompt to add a prompt/message")
+	}
+
+	copilotModel := StripGitHubModelPrefix(ghcpResolveModel(c.endpoint.Model))
+	if err := ghcpCheckRequestAllowed(copilotModel); err != nil {
+		return nil, err
+	}
+
+	stdio := telemetry.SpanStdio(ctx, InstrumentationLibrary)
+	defer stdio.Close()
+
+	ctx, span, endSpan := startGenAISpan(ctx, string(c.endpoint.Provider), copilotModel, c.endpoint.Alias)
+	var usage LLMTokenUsage
+	defer func() { endSpan(usage, rerr) }()
+
+	m := telemetry.Meter(ctx, InstrumentationLibrary)
+	spanCtx := trace.SpanContextFromContext(ctx)
+	attrs := []attribute.KeyValue{
+		attribute.String(telemetry.MetricsTraceIDAttr, spanCtx.TraceID().String()),
+		attribute.String(telemetry.MetricsSpanIDAttr, spanCtx.SpanID().String()),
+		attribute.String("model", copilotModel),
+		attribute.String("provider", string(c.endpoint.Provider)),
+		attribute.String("alias", c.endpoint.Alias),
+	}
+
+	inputTokens, err := m.Int64Gauge(telemetry.LLMInputTokens)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inputTokens gauge: %w", err)
+	}
+	outputTokens, err := m.Int64Gauge(telemetry.LLMOutputTokens)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get outputTokens gauge: %w", err)
+	}
+
+	reqBody := ghcpChatRequest{
+		Model:         copilotModel,
+		Messages:      toGhcpMessages(history),
+		Tools:         toGhcpTools(tools),
+		Stream:        true,
+		StreamOptions: &ghcpStreamOptions{IncludeUsage: true},
+	}
+	payload, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal github copilot request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/chat/completions", bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build github copilot request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.endpoint.Key)
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("github copilot http request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, &ghcpHTTPError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	var content strings.Builder
+	toolCallsByIndex := map[int]*LLMToolCall{}
+	var toolCallOrder []int
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk ghcpChatResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("failed to parse github copilot stream chunk: %w", err)
+		}
+
+		if chunk.Usage.CompletionTokens > 0 || chunk.Usage.PromptTokens > 0 {
+			usage.InputTokens = chunk.Usage.PromptTokens
+			usage.OutputTokens = chunk.Usage.CompletionTokens
+			usage.CachedTokenReads = chunk.Usage.PromptTokensDetails.CachedTokens
+			usage.TotalTokens = chunk.Usage.TotalTokens
+		}
+
+		for _, choice := range chunk.Choices {
+			content.WriteString(choice.Delta.Content)
+			for _, tc := range choice.Delta.ToolCalls {
+				idx := tc.Index
+				existing, ok := toolCallsByIndex[idx]
+				if !ok {
+					existing = &LLMToolCall{ID: tc.ID, Function: LLMToolCallFunction{Name: tc.Function.Name}}
+					toolCallsByIndex[idx] = existing
+					toolCallOrder = append(toolCallOrder, idx)
+				}
+				existing.Function.Arguments += tc.Function.Arguments
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read github copilot stream: %w", err)
+	}
+
+	if usage.TotalTokens == 0 {
+		usage.TotalTokens = usage.InputTokens + usage.OutputTokens
+	}
+	inputTokens.Record(ctx, usage.InputTokens, metric.WithAttributes(attrs...))
+	outputTokens.Record(ctx, usage.OutputTokens, metric.WithAttributes(attrs...))
+
+	toolCalls := make([]LLMToolCall, 0, len(toolCallOrder))
+	for _, idx := range toolCallOrder {
+		toolCalls = append(toolCalls, *toolCallsByIndex[idx])
+	}
+
+	recordGenAIContentEvent(span, "gen_ai.content.prompt", history[len(history)-1].Content)
+	recordGenAIContentEvent(span, "gen_ai.content.completion", content.String())
+
+	return &LLMResponse{
+		Content:    content.String(),
+		ToolCalls:  toolCalls,
+		TokenUsage: usage,
+	}, nil
+}
+
+// IsRetryable reports whether err came from a transient GitHub Models failure (429 rate
+// limiting or a 5xx server error), in which case the caller's retry/backoff loop should
+// try again.
+func (c *GhcpHTTPClient) IsRetryable(err error) bool {
+	var httpErr *ghcpHTTPError
+	if !errors.As(err, &httpErr) {
+		return false
+	}
+	return httpErr.StatusCode == http.StatusTooManyRequests || httpErr.StatusCode >= 500
+}
@@ -0,0 +1,114 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// sseToolCallDelta builds a ghcpToolCall delta for use in a test SSE chunk.
+func sseToolCallDelta(index int, id, name, args string) ghcpToolCall {
+	var tc ghcpToolCall
+	tc.Index = index
+	tc.ID = id
+	tc.Type = "function"
+	tc.Function.Name = name
+	tc.Function.Arguments = args
+	return tc
+}
+
+// sseChunk marshals resp as a single "data: ...\n\n" SSE event.
+func sseChunk(t *testing.T, resp ghcpChatResponse) string {
+	t.Helper()
+	b, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatalf("failed to marshal test SSE chunk: %v", err)
+	}
+	return "data: " + string(b) + "\n\n"
+}
+
+func newTestGhcpHTTPClient(baseURL string) *GhcpHTTPClient {
+	return &GhcpHTTPClient{
+		httpClient: http.DefaultClient,
+		endpoint:   &LLMEndpoint{Provider: "github", Model: "gpt-4", Key: "test-key"},
+		baseURL:    baseURL,
+	}
+}
+
+func TestGhcpHTTPClient_SendQuery_AccumulatesToolCallsByIndex(t *testing.T) {
+	var body strings.Builder
+	// Two tool calls interleave across chunks, keyed by the "index" field rather than
+	// slice position, plus a continuation chunk that only carries more arguments for
+	// the first tool call.
+	body.WriteString(sseChunk(t, ghcpChatResponse{Choices: []ghcpChatChoice{{
+		Delta: ghcpChatMessage{ToolCalls: []ghcpToolCall{sseToolCallDelta(0, "call_1", "get_weather", `{"loc`)}},
+	}}}))
+	body.WriteString(sseChunk(t, ghcpChatResponse{Choices: []ghcpChatChoice{{
+		Delta: ghcpChatMessage{ToolCalls: []ghcpToolCall{sseToolCallDelta(1, "call_2", "get_time", `{}`)}},
+	}}}))
+	body.WriteString(sseChunk(t, ghcpChatResponse{Choices: []ghcpChatChoice{{
+		Delta: ghcpChatMessage{ToolCalls: []ghcpToolCall{sseToolCallDelta(0, "", "", `ation":"NYC"}`)}},
+	}}}))
+	body.WriteString("data: [DONE]\n\n")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(body.String()))
+	}))
+	defer server.Close()
+
+	client := newTestGhcpHTTPClient(server.URL)
+	resp, err := client.SendQuery(context.Background(), []*ModelMessage{{Role: "user", Content: "what's the weather and time?"}}, nil)
+	if err != nil {
+		t.Fatalf("SendQuery returned an error: %v", err)
+	}
+
+	if len(resp.ToolCalls) != 2 {
+		t.Fatalf("expected 2 tool calls, got %d: %+v", len(resp.ToolCalls), resp.ToolCalls)
+	}
+	if resp.ToolCalls[0].ID != "call_1" || resp.ToolCalls[0].Function.Arguments != `{"location":"NYC"}` {
+		t.Fatalf("expected call_1's arguments to accumulate across its two deltas, got %+v", resp.ToolCalls[0])
+	}
+	if resp.ToolCalls[1].ID != "call_2" || resp.ToolCalls[1].Function.Arguments != `{}` {
+		t.Fatalf("expected call_2 to stay separate from call_1, got %+v", resp.ToolCalls[1])
+	}
+}
+
+func TestGhcpHTTPClient_SendQuery_UsageOnlyFinalChunk(t *testing.T) {
+	var body strings.Builder
+	body.WriteString(sseChunk(t, ghcpChatResponse{Choices: []ghcpChatChoice{{
+		Delta: ghcpChatMessage{Content: "the weather is sunny"},
+	}}}))
+	// The final chunk of a stream_options.include_usage response carries usage but an
+	// empty choices list.
+	usageChunk := ghcpChatResponse{}
+	usageChunk.Usage.PromptTokens = 42
+	usageChunk.Usage.CompletionTokens = 7
+	usageChunk.Usage.TotalTokens = 49
+	body.WriteString(sseChunk(t, usageChunk))
+	body.WriteString("data: [DONE]\n\n")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(body.String()))
+	}))
+	defer server.Close()
+
+	client := newTestGhcpHTTPClient(server.URL)
+	resp, err := client.SendQuery(context.Background(), []*ModelMessage{{Role: "user", Content: "what's the weather?"}}, nil)
+	if err != nil {
+		t.Fatalf("SendQuery returned an error: %v", err)
+	}
+
+	if resp.Content != "the weather is sunny" {
+		t.Fatalf("expected accumulated content, got %q", resp.Content)
+	}
+	if resp.TokenUsage.InputTokens != 42 || resp.TokenUsage.OutputTokens != 7 || resp.TokenUsage.TotalTokens != 49 {
+		t.Fatalf("expected usage from the final usage-only chunk, got %+v", resp.TokenUsage)
+	}
+}
@@ -0,0 +1,306 @@
+package core
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// remoteConfigURLEnvVar points at an HTTPS endpoint returning a signed GhcpRemoteConfig
+// document. remoteConfigFileEnvVar is a local-file alternative for air-gapped installs
+// that can't reach a control plane; it takes precedence when set. remoteConfigPubKeyEnvVar
+// holds the base64-encoded Ed25519 public key the document's signature is checked
+// against; without it, a fetched config can't be trusted and is rejected.
+const (
+	remoteConfigURLEnvVar      = "DAGGER_GHCP_CONFIG_URL"
+	remoteConfigFileEnvVar     = "DAGGER_GHCP_CONFIG_FILE"
+	remoteConfigRefreshEnvVar  = "DAGGER_GHCP_CONFIG_REFRESH_INTERVAL"
+	remoteConfigPubKeyEnvVar   = "DAGGER_GHCP_CONFIG_PUBKEY"
+	defaultRemoteConfigRefresh = 5 * time.Minute
+)
+
+// ghcpSignedConfig is the envelope fetched from the control plane (or a local file):
+// the config payload plus a detached Ed25519 signature over it, so a compromised
+// control plane or a tampered local file can't silently push a config change.
+type ghcpSignedConfig struct {
+	Payload   json.RawMessage `json:"payload"`
+	Signature string          `json:"signature"`
+}
+
+// verifyGhcpConfigSignature checks sig (base64-encoded) against payload using the
+// Ed25519 public key configured via DAGGER_GHCP_CONFIG_PUBKEY (also base64-encoded).
+func verifyGhcpConfigSignature(payload []byte, sig string) error {
+	pubKeyB64 := os.Getenv(remoteConfigPubKeyEnvVar)
+	if pubKeyB64 == "" {
+		return fmt.Errorf("github copilot remote config: %s must be set to verify a fetched config", remoteConfigPubKeyEnvVar)
+	}
+	pubKey, err := base64.StdEncoding.DecodeString(pubKeyB64)
+	if err != nil || len(pubKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("github copilot remote config: %s is not a valid ed25519 public key", remoteConfigPubKeyEnvVar)
+	}
+	sigBytes, err := base64.StdEncoding.DecodeString(sig)
+	if err != nil {
+		return fmt.Errorf("github copilot remote config: signature is not valid base64: %w", err)
+	}
+	if !ed25519.Verify(ed25519.PublicKey(pubKey), payload, sigBytes) {
+		return fmt.Errorf("github copilot remote config: signature verification failed")
+	}
+	return nil
+}
+
+// GhcpRemoteConfig is the document fetched from the control plane (or a local file) that
+// lets operators roll out a new copilot CLI version or block a misbehaving model across
+// a fleet without shipping a new Dagger binary.
+type GhcpRemoteConfig struct {
+	// Models is the allow-list of models pipeline authors may select for provider=github.
+	Models []string `json:"models"`
+	// DefaultModel is used when an LLMEndpoint doesn't specify one (see ghcpResolveModel).
+	DefaultModel string `json:"defaultModel"`
+	// ModelPrefixes overrides the hard-coded gitHubModelPrefixes when non-empty.
+	ModelPrefixes []string `json:"modelPrefixes"`
+	// CLIVersion pins the @github/copilot version installed in GhcpClientContainer.
+	CLIVersion string `json:"cliVersion"`
+	// RateLimitRPM caps requests/minute across all GHCP endpoints in the process (see
+	// ghcpLimiter); zero means unlimited.
+	RateLimitRPM int `json:"rateLimitRPM"`
+}
+
+// validate rejects a config document that would leave GHCP unusable, e.g. one with no
+// models or an empty default model, before it replaces the in-memory config.
+func (c *GhcpRemoteConfig) validate() error {
+	if len(c.Models) == 0 {
+		return fmt.Errorf("github copilot remote config: models list cannot be empty")
+	}
+	if c.DefaultModel == "" {
+		return fmt.Errorf("github copilot remote config: defaultModel cannot be empty")
+	}
+	if c.CLIVersion == "" {
+		return fmt.Errorf("github copilot remote config: cliVersion cannot be empty")
+	}
+	return nil
+}
+
+// ghcpConfigStore holds the active GhcpRemoteConfig, which StartGhcpRemoteConfigRefresh
+// replaces periodically from whatever source was configured at startup. The zero value
+// is safe to use: Snapshot returns nil until a fetch succeeds, and callers then use the
+// static defaults (gitHubModelPrefixes, etc.).
+type ghcpConfigStore struct {
+	mu     sync.RWMutex
+	active *GhcpRemoteConfig
+}
+
+var ghcpConfig = &ghcpConfigStore{}
+
+// Snapshot returns the currently active remote config, or nil if none has been fetched
+// (or configured) yet, in which case callers should use their static defaults.
+func (s *ghcpConfigStore) Snapshot() *GhcpRemoteConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.active
+}
+
+func (s *ghcpConfigStore) set(cfg *GhcpRemoteConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.active = cfg
+}
+
+// StartGhcpRemoteConfigRefresh launches a background goroutine that periodically fetches
+// the GHCP remote config (see fetchGhcpRemoteConfig for source precedence) and swaps it
+// into the global store once validated. It returns immediately if no source is
+// configured. Callers should run this once at engine startup.
+func StartGhcpRemoteConfigRefresh(ctx context.Context) {
+	if os.Getenv(remoteConfigURLEnvVar) == "" && os.Getenv(remoteConfigFileEnvVar) == "" {
+		return
+	}
+
+	interval := defaultRemoteConfigRefresh
+	if raw := os.Getenv(remoteConfigRefreshEnvVar); raw != "" {
+		if parsed, err := time.ParseDuration(raw); err == nil {
+			interval = parsed
+		}
+	}
+
+	refresh := func() {
+		cfg, err := fetchGhcpRemoteConfig(ctx)
+		if err != nil {
+			// Keep serving the last known-good config (or static defaults) on failure.
+			return
+		}
+		ghcpConfig.set(cfg)
+	}
+
+	refresh()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				refresh()
+			}
+		}
+	}()
+}
+
+// fetchGhcpRemoteConfig loads and validates a GhcpRemoteConfig from the local file
+// source (DAGGER_GHCP_CONFIG_FILE) if set, otherwise from the HTTPS control-plane
+// endpoint (DAGGER_GHCP_CONFIG_URL).
+func fetchGhcpRemoteConfig(ctx context.Context) (*GhcpRemoteConfig, error) {
+	var payload []byte
+	var err error
+
+	if path := os.Getenv(remoteConfigFileEnvVar); path != "" {
+		payload, err = os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read github copilot remote config file: %w", err)
+		}
+	} else {
+		url := os.Getenv(remoteConfigURLEnvVar)
+		req, reqErr := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if reqErr != nil {
+			return nil, fmt.Errorf("failed to build github copilot remote config request: %w", reqErr)
+		}
+
+		client := &http.Client{
+			Timeout: 10 * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{MinVersion: tls.VersionTLS12},
+			},
+		}
+		resp, doErr := client.Do(req)
+		if doErr != nil {
+			return nil, fmt.Errorf("failed to fetch github copilot remote config: %w", doErr)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("github copilot remote config endpoint returned status %d", resp.StatusCode)
+		}
+
+		payload, err = io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read github copilot remote config response: %w", err)
+		}
+	}
+
+	var signed ghcpSignedConfig
+	if err := json.Unmarshal(payload, &signed); err != nil {
+		return nil, fmt.Errorf("failed to parse github copilot remote config envelope: %w", err)
+	}
+	if err := verifyGhcpConfigSignature(signed.Payload, signed.Signature); err != nil {
+		return nil, err
+	}
+
+	var cfg GhcpRemoteConfig
+	if err := json.Unmarshal(signed.Payload, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse github copilot remote config: %w", err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+// ghcpModelPrefixes returns the active model prefixes, preferring the remote config's
+// ModelPrefixes when one has been fetched, and falling back to the static
+// gitHubModelPrefixes otherwise.
+func ghcpModelPrefixes() []string {
+	if cfg := ghcpConfig.Snapshot(); cfg != nil && len(cfg.ModelPrefixes) > 0 {
+		return cfg.ModelPrefixes
+	}
+	return gitHubModelPrefixes
+}
+
+// ghcpCLIVersion returns the CLI version to install in GhcpClientContainer, preferring
+// the remote config's pinned version over fallback when one has been fetched.
+func ghcpCLIVersion(fallback string) string {
+	if cfg := ghcpConfig.Snapshot(); cfg != nil && cfg.CLIVersion != "" {
+		return cfg.CLIVersion
+	}
+	return fallback
+}
+
+// ghcpResolveModel returns the model to request: the endpoint's own Model, or the
+// remote config's DefaultModel when the endpoint doesn't specify one.
+func ghcpResolveModel(endpointModel string) string {
+	if endpointModel != "" {
+		return endpointModel
+	}
+	if cfg := ghcpConfig.Snapshot(); cfg != nil {
+		return cfg.DefaultModel
+	}
+	return endpointModel
+}
+
+// LLMConfig exposes the currently active GHCP remote config for debugging, e.g. via the
+// dagger.Client.LLMConfig() query, so operators can confirm a fleet-wide rollout landed
+// without having to check engine logs.
+func LLMConfig() *GhcpRemoteConfig {
+	return ghcpConfig.Snapshot()
+}
+
+// ghcpRateLimiter tracks recent request timestamps in a sliding one-minute window so
+// ghcpCheckRequestAllowed can enforce the remote config's RateLimitRPM across both GHCP
+// transports (GhcpClient and GhcpHTTPClient share the same process-wide limiter).
+type ghcpRateLimiter struct {
+	mu   sync.Mutex
+	hits []time.Time
+}
+
+var ghcpLimiter = &ghcpRateLimiter{}
+
+// allow reports whether a request may proceed given rpm requests/minute, recording this
+// request if so. rpm <= 0 means unlimited.
+func (l *ghcpRateLimiter) allow(rpm int) bool {
+	if rpm <= 0 {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	cutoff := time.Now().Add(-time.Minute)
+	live := l.hits[:0]
+	for _, t := range l.hits {
+		if t.After(cutoff) {
+			live = append(live, t)
+		}
+	}
+	l.hits = live
+
+	if len(l.hits) >= rpm {
+		return false
+	}
+	l.hits = append(l.hits, time.Now())
+	return true
+}
+
+// ghcpCheckRequestAllowed enforces the active remote config's Models allow-list and
+// RateLimitRPM against a single outgoing request. It is a no-op until a remote config
+// has been fetched.
+func ghcpCheckRequestAllowed(model string) error {
+	cfg := ghcpConfig.Snapshot()
+	if cfg == nil {
+		return nil
+	}
+	if len(cfg.Models) > 0 && !slices.Contains(cfg.Models, model) {
+		return fmt.Errorf("github copilot: model %q is not in the fleet allow-list", model)
+	}
+	if !ghcpLimiter.allow(cfg.RateLimitRPM) {
+		return fmt.Errorf("github copilot: rate limit of %d requests/minute exceeded", cfg.RateLimitRPM)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package core
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"testing"
+)
+
+func TestVerifyGhcpConfigSignature(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("failed to generate test key: %v", err)
+	}
+	t.Setenv(remoteConfigPubKeyEnvVar, base64.StdEncoding.EncodeToString(pub))
+
+	payload := []byte(`{"models":["gpt-4"],"defaultModel":"gpt-4","cliVersion":"1.2.3"}`)
+	sig := base64.StdEncoding.EncodeToString(ed25519.Sign(priv, payload))
+
+	if err := verifyGhcpConfigSignature(payload, sig); err != nil {
+		t.Fatalf("expected a valid signature to verify, got: %v", err)
+	}
+}
+
+func TestVerifyGhcpConfigSignature_BadSignature(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("failed to generate test key: %v", err)
+	}
+	t.Setenv(remoteConfigPubKeyEnvVar, base64.StdEncoding.EncodeToString(pub))
+
+	payload := []byte(`{"models":["gpt-4"],"defaultModel":"gpt-4","cliVersion":"1.2.3"}`)
+	sig := base64.StdEncoding.EncodeToString(ed25519.Sign(priv, []byte("a different payload")))
+
+	if err := verifyGhcpConfigSignature(payload, sig); err == nil {
+		t.Fatal("expected a signature over a different payload to fail verification")
+	}
+}
+
+func TestVerifyGhcpConfigSignature_MissingPubKey(t *testing.T) {
+	t.Setenv(remoteConfigPubKeyEnvVar, "")
+
+	payload := []byte(`{"models":["gpt-4"],"defaultModel":"gpt-4","cliVersion":"1.2.3"}`)
+	if err := verifyGhcpConfigSignature(payload, "does-not-matter"); err == nil {
+		t.Fatal("expected verification to fail when no public key is configured")
+	}
+}
+
+func TestGhcpCheckRequestAllowed_DisallowedModel(t *testing.T) {
+	prev := ghcpConfig
+	t.Cleanup(func() { ghcpConfig = prev })
+	ghcpConfig = &ghcpConfigStore{}
+	ghcpConfig.set(&GhcpRemoteConfig{Models: []string{"gpt-4"}, DefaultModel: "gpt-4", CLIVersion: "1.2.3"})
+
+	if err := ghcpCheckRequestAllowed("gpt-4-turbo"); err == nil {
+		t.Fatal("expected a model outside the allow-list to be rejected")
+	}
+	if err := ghcpCheckRequestAllowed("gpt-4"); err != nil {
+		t.Fatalf("expected an allow-listed model to pass, got: %v", err)
+	}
+}
+
+func TestGhcpCheckRequestAllowed_RateLimit(t *testing.T) {
+	prev, prevLimiter := ghcpConfig, ghcpLimiter
+	t.Cleanup(func() { ghcpConfig, ghcpLimiter = prev, prevLimiter })
+	ghcpConfig = &ghcpConfigStore{}
+	ghcpLimiter = &ghcpRateLimiter{}
+	ghcpConfig.set(&GhcpRemoteConfig{Models: []string{"gpt-4"}, DefaultModel: "gpt-4", CLIVersion: "1.2.3", RateLimitRPM: 1})
+
+	if err := ghcpCheckRequestAllowed("gpt-4"); err != nil {
+		t.Fatalf("expected the first request within the limit to pass, got: %v", err)
+	}
+	if err := ghcpCheckRequestAllowed("gpt-4"); err == nil {
+		t.Fatal("expected the second request to exceed a 1 request/minute limit")
+	}
+}
+
+func TestGhcpCheckRequestAllowed_NoConfigIsNoop(t *testing.T) {
+	prev := ghcpConfig
+	t.Cleanup(func() { ghcpConfig = prev })
+	ghcpConfig = &ghcpConfigStore{}
+
+	if err := ghcpCheckRequestAllowed("anything"); err != nil {
+		t.Fatalf("expected no remote config to mean no enforcement, got: %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package core
+
+import "testing"
+
+func TestParseCopilotJSONTokenMetadata(t *testing.T) {
+	stderr := `{"level":"debug","msg":"starting request"}
+{"model":"claude-sonnet-4.5","request_id":"abc123","usage":{"input":7500,"output":52,"cache_read":3600,"cache_write":3700}}
+`
+	usage, ok := parseCopilotJSONTokenMetadata(stderr)
+	if !ok {
+		t.Fatalf("expected ok=true, got false")
+	}
+	if usage.InputTokens != 7500 || usage.OutputTokens != 52 || usage.CachedTokenReads != 3600 || usage.CachedTokenWrites != 3700 {
+		t.Fatalf("unexpected usage: %+v", usage)
+	}
+	if usage.TotalTokens != 7552 {
+		t.Fatalf("expected TotalTokens 7552, got %d", usage.TotalTokens)
+	}
+}
+
+func TestParseCopilotJSONTokenMetadata_LastEventWins(t *testing.T) {
+	stderr := `{"model":"claude-sonnet-4.5","usage":{"input":100,"output":10}}
+{"model":"claude-sonnet-4.5","usage":{"input":200,"output":20}}
+`
+	usage, ok := parseCopilotJSONTokenMetadata(stderr)
+	if !ok {
+		t.Fatalf("expected ok=true, got false")
+	}
+	if usage.InputTokens != 200 || usage.OutputTokens != 20 {
+		t.Fatalf("expected the last event's usage to win, got %+v", usage)
+	}
+}
+
+func TestParseCopilotTokenMetadata_RegexFallback(t *testing.T) {
+	stderr := "claude-sonnet-4.5    7.5k input, 52 output, 3.6k cache read, 3.7k cache write (Est. 1 Premium request)"
+	usage := parseCopilotTokenMetadata(stderr)
+	if usage.InputTokens != 7500 || usage.OutputTokens != 52 || usage.CachedTokenReads != 3600 || usage.CachedTokenWrites != 3700 {
+		t.Fatalf("unexpected usage: %+v", usage)
+	}
+}
+
+func TestParseCopilotTokenMetadata_RegexFallback_NoCacheWrite(t *testing.T) {
+	stderr := "claude-sonnet-4.5    10 input, 5 output, 2 cache read (Est. 1 Premium request)"
+	usage := parseCopilotTokenMetadata(stderr)
+	if usage.InputTokens != 10 || usage.OutputTokens != 5 || usage.CachedTokenReads != 2 || usage.CachedTokenWrites != 0 {
+		t.Fatalf("unexpected usage: %+v", usage)
+	}
+}
+
+// TestParseCopilotTokenUsage_FallsBackOnMalformedJSON covers older copilot CLI builds
+// that emit partial or invalid JSON lines (e.g. a truncated --log-format json line from
+// a version that doesn't fully support it) alongside the legacy human-formatted summary;
+// parseCopilotTokenUsage should skip the bad lines and fall back to the regex parser.
+func TestParseCopilotTokenUsage_FallsBackOnMalformedJSON(t *testing.T) {
+	stderr := `{"model":"claude-sonnet-4.5","request_id"
+claude-sonnet-4.5    7.5k input, 52 output, 3.6k cache read, 3.7k cache write (Est. 1 Premium request)
+`
+	usage := parseCopilotTokenUsage(stderr)
+	if usage.InputTokens != 7500 || usage.OutputTokens != 52 {
+		t.Fatalf("expected regex fallback to apply, got %+v", usage)
+	}
+}
+
+func TestParseCopilotTokenUsage_NoUsageFound(t *testing.T) {
+	stderr := "copilot: no recognizable usage line here"
+	usage := parseCopilotTokenUsage(stderr)
+	if usage.InputTokens != 0 || usage.OutputTokens != 0 {
+		t.Fatalf("expected zero usage, got %+v", usage)
+	}
+}
+
+func TestParseCopilotJSONError(t *testing.T) {
+	stderr := `{"model":"claude-sonnet-4.5","request_id":"abc123","usage":{"input":10,"output":5}}
+{"model":"claude-sonnet-4.5","request_id":"abc123","error":"upstream rate limited"}
+`
+	if got := parseCopilotJSONError(stderr); got != "upstream rate limited" {
+		t.Fatalf("expected the reported error, got %q", got)
+	}
+}
+
+func TestParseCopilotJSONError_NoError(t *testing.T) {
+	stderr := `{"model":"claude-sonnet-4.5","usage":{"input":10,"output":5}}`
+	if got := parseCopilotJSONError(stderr); got != "" {
+		t.Fatalf("expected no error, got %q", got)
+	}
+}